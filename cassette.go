@@ -0,0 +1,265 @@
+package webmock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RecordFilter mutates a captured request/response pair before it is
+// written to the cassette, e.g. to redact an Authorization header, cookies
+// or a dynamic timestamp.
+type RecordFilter func(*http.Request, *http.Response)
+
+// RecordOption configures a MockServer.Record session.
+type RecordOption func(*recordSession)
+
+// WithRecordFilter registers a filter that runs on every captured exchange
+// before it's appended to the cassette.
+func WithRecordFilter(filter RecordFilter) RecordOption {
+	return func(rs *recordSession) {
+		rs.filters = append(rs.filters, filter)
+	}
+}
+
+// WithRecordOnce stops capturing a given request signature (method, path
+// and query) once it has been recorded once, so cassettes don't grow a
+// duplicate entry every time a test repeats the same call.
+func WithRecordOnce() RecordOption {
+	return func(rs *recordSession) {
+		rs.once = true
+	}
+}
+
+// recordSession holds the state for a single MockServer.Record passthrough.
+type recordSession struct {
+	upstream     *url.URL
+	client       *http.Client
+	cassettePath string
+	filters      []RecordFilter
+	once         bool
+
+	mu   sync.Mutex
+	seen map[string]bool
+	// recorded tracks the entries this session itself has appended, for
+	// introspection; it is not the source of truth for the cassette file,
+	// since other sessions (or a second Record call against the same path)
+	// may be appending to it concurrently. See appendCassetteEntry.
+	recorded cassetteRoutes
+}
+
+// cassetteFileLocks serializes reads and writes against a cassette file
+// path across every recordSession writing to it, so concurrent Record
+// sessions (even from different MockServers) don't clobber each other's
+// appends the way independent per-session snapshots would.
+var cassetteFileLocks sync.Map
+
+func cassetteFileLock(filePath string) *sync.Mutex {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		abs = filePath
+	}
+
+	lock, _ := cassetteFileLocks.LoadOrStore(abs, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// appendCassetteEntry appends entry to the cassette at path, re-reading the
+// file's current contents under the path's lock so appends from concurrent
+// sessions are never lost.
+func appendCassetteEntry(filePath string, entry cassetteRoute) error {
+	lock := cassetteFileLock(filePath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	routes := append(readExistingCassette(filePath), entry)
+
+	return writeCassetteFile(filePath, routes)
+}
+
+// Record puts the server into passthrough mode: any request that doesn't
+// match an existing stub is proxied to upstreamURL and the request/response
+// pair is appended to the YAML cassette at cassettePath, using the same
+// schema LoadCassette understands.
+func (s *MockServer) Record(upstreamURL, cassettePath string, options ...RecordOption) {
+	upstream, err := url.Parse(upstreamURL)
+	if err != nil {
+		log.Fatal("invalid upstream url: ", err)
+	}
+
+	rs := &recordSession{
+		upstream:     upstream,
+		client:       &http.Client{},
+		cassettePath: cassettePath,
+		seen:         make(map[string]bool),
+	}
+
+	for _, opt := range options {
+		opt(rs)
+	}
+
+	s.recording = rs
+	s.server.Handler = s
+}
+
+// recordAndProxy forwards r to the configured upstream, replays the
+// response to w and appends the exchange to the cassette.
+func (s *MockServer) recordAndProxy(w http.ResponseWriter, r *http.Request) {
+	rs := s.recording
+
+	var reqBody []byte
+	if r.Body != nil {
+		reqBody, _ = ioutil.ReadAll(r.Body)
+	}
+
+	upstreamURL := *rs.upstream
+	upstreamURL.Path = path.Join(upstreamURL.Path, r.URL.Path)
+	upstreamURL.RawQuery = r.URL.RawQuery
+
+	proxyReq, err := http.NewRequest(r.Method, upstreamURL.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		log.Print("record: building upstream request failed: ", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	proxyReq.Header = r.Header.Clone()
+
+	resp, err := rs.client.Do(proxyReq)
+	if err != nil {
+		log.Print("record: upstream request failed: ", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Print("record: reading upstream response failed: ", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	for headerKey, headerVals := range resp.Header {
+		for _, v := range headerVals {
+			w.Header().Add(headerKey, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+
+	rs.capture(r, reqBody, resp, respBody)
+}
+
+// capture applies the configured filters and appends the exchange to the
+// cassette file. The append goes through appendCassetteEntry, which
+// re-reads the file under a path-keyed lock rather than writing from this
+// session's own cached copy, so concurrent Record sessions targeting the
+// same cassette path don't overwrite each other's entries.
+func (rs *recordSession) capture(r *http.Request, reqBody []byte, resp *http.Response, body []byte) {
+	signature := r.Method + " " + pathWithQuery(r.URL)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.once && rs.seen[signature] {
+		return
+	}
+
+	reqCopy := &http.Request{
+		Method: r.Method,
+		URL:    r.URL,
+		Header: r.Header.Clone(),
+		Body:   ioutil.NopCloser(bytes.NewReader(reqBody)),
+	}
+	respCopy := &http.Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}
+
+	for _, filter := range rs.filters {
+		filter(reqCopy, respCopy)
+	}
+
+	finalReqBody, err := ioutil.ReadAll(reqCopy.Body)
+	if err != nil {
+		finalReqBody = reqBody
+	}
+
+	finalBody, err := ioutil.ReadAll(respCopy.Body)
+	if err != nil {
+		finalBody = body
+	}
+
+	entry := cassetteRoute{
+		Request: httpRequest{
+			Method:  reqCopy.Method,
+			Path:    pathWithQuery(reqCopy.URL),
+			Headers: headersToMap(reqCopy.Header),
+			Body:    string(finalReqBody),
+		},
+		Response: httpResponse{
+			Status:  respCopy.StatusCode,
+			Headers: headersToMap(respCopy.Header),
+			Body:    string(finalBody),
+		},
+	}
+
+	rs.recorded = append(rs.recorded, entry)
+	rs.seen[signature] = true
+
+	if err := appendCassetteEntry(rs.cassettePath, entry); err != nil {
+		log.Print("record: writing cassette failed: ", err)
+	}
+}
+
+// readExistingCassette loads a cassette's existing entries, or returns nil
+// if the file doesn't exist yet or can't be parsed as one.
+func readExistingCassette(filePath string) cassetteRoutes {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var cassettes cassetteRoutes
+	if err := yaml.NewDecoder(file).Decode(&cassettes); err != nil {
+		return nil
+	}
+
+	return cassettes
+}
+
+func writeCassetteFile(filePath string, routes cassetteRoutes) error {
+	data, err := yaml.Marshal(routes)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+func pathWithQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+
+	return u.Path + "?" + u.RawQuery
+}
+
+func headersToMap(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key := range header {
+		headers[key] = header.Get(key)
+	}
+
+	return headers
+}
@@ -0,0 +1,116 @@
+package webmock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRecordCapturesRequestBody(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer upstream.Close()
+
+	cassettePath := t.TempDir() + "/cassette.yml"
+
+	s := New()
+	s.Record(upstream.URL, cassettePath)
+
+	req := httptest.NewRequest("POST", "/things", bytes.NewBufferString(`{"name":"Alex"}`))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status=%d body=%q", rec.Code, rec.Body.String())
+	}
+
+	if len(s.recording.recorded) != 1 {
+		t.Fatalf("recorded entries = %d, want 1", len(s.recording.recorded))
+	}
+
+	if got, want := s.recording.recorded[0].Request.Body, `{"name":"Alex"}`; got != want {
+		t.Errorf("recorded request body = %q, want %q", got, want)
+	}
+}
+
+// TestRecordConcurrentSessionsAppendToSameCassette guards against
+// concurrent Record sessions targeting the same cassette path clobbering
+// each other's appends: each session used to snapshot the file's contents
+// once at Record() time and rewrite the whole file from that stale
+// snapshot, so only the last writer's entry would survive.
+func TestRecordConcurrentSessionsAppendToSameCassette(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cassettePath := t.TempDir() + "/cassette.yml"
+
+	const sessions = 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			s := New()
+			s.Record(upstream.URL, cassettePath)
+
+			req := httptest.NewRequest("GET", "/things", nil)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+		}(i)
+	}
+	wg.Wait()
+
+	routes := readExistingCassette(cassettePath)
+	if len(routes) != sessions {
+		t.Fatalf("cassette entries = %d, want %d", len(routes), sessions)
+	}
+}
+
+// TestLoadCassetteMatchesRequestBody guards the other half of the
+// round-trip: a cassette entry with a request body should produce a
+// route that only matches requests carrying that exact body.
+func TestLoadCassetteMatchesRequestBody(t *testing.T) {
+	dir := t.TempDir()
+	cassettePath := dir + "/cassette.yml"
+
+	if err := ioutil.WriteFile(cassettePath, []byte(`
+- request:
+    method: POST
+    path: /things
+    body: '{"name":"Alex"}'
+  response:
+    status: 201
+    body: created
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+	s.LoadCassette(cassettePath)
+
+	match := httptest.NewRequest("POST", "/things", bytes.NewBufferString(`{"name":"Alex"}`))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, match)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("matching body: status=%d, want %d", rec.Code, http.StatusCreated)
+	}
+
+	mismatch := httptest.NewRequest("POST", "/things", bytes.NewBufferString(`{"name":"Someone Else"}`))
+	rec2 := httptest.NewRecorder()
+	s.ServeHTTP(rec2, mismatch)
+
+	if rec2.Code != http.StatusNotFound {
+		t.Errorf("mismatching body: status=%d, want %d", rec2.Code, http.StatusNotFound)
+	}
+}
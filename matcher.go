@@ -0,0 +1,64 @@
+package webmock
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+)
+
+// bodyMatcher reports whether a request body satisfies a stub's
+// expectation. It receives the request alongside the body so a matcher
+// can take the Content-Type header into account.
+type bodyMatcher func(body []byte, r *http.Request) bool
+
+// WithRequestBody matches a stub only when the request body is exactly
+// expected.
+func WithRequestBody(expected string) FuncOption {
+	return func(r *route) {
+		r.requestBodyMatcher = exactBodyMatcher(expected)
+	}
+}
+
+// WithRequestBodyRegex matches a stub only when the request body matches
+// the given regular expression.
+func WithRequestBodyRegex(pattern string) FuncOption {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Fatal("invalid regex for WithRequestBodyRegex: ", err)
+	}
+
+	return func(r *route) {
+		r.requestBodyMatcher = func(body []byte, _ *http.Request) bool {
+			return re.Match(body)
+		}
+	}
+}
+
+// WithRequestBodyJSON matches a stub only when the request body is
+// structurally equal to expected JSON, so key order and insignificant
+// whitespace don't cause spurious mismatches.
+func WithRequestBodyJSON(expected string) FuncOption {
+	var expectedValue interface{}
+	if err := json.Unmarshal([]byte(expected), &expectedValue); err != nil {
+		log.Fatal("invalid json for WithRequestBodyJSON: ", err)
+	}
+
+	return func(r *route) {
+		r.requestBodyMatcher = func(body []byte, _ *http.Request) bool {
+			var actualValue interface{}
+			if err := json.Unmarshal(body, &actualValue); err != nil {
+				return false
+			}
+
+			return reflect.DeepEqual(expectedValue, actualValue)
+		}
+	}
+}
+
+func exactBodyMatcher(expected string) bodyMatcher {
+	return func(body []byte, _ *http.Request) bool {
+		return string(body) == expected
+	}
+}
@@ -0,0 +1,43 @@
+package webmock
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithRequestBodyMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  FuncOption
+		body    string
+		matches bool
+	}{
+		{"exact match", WithRequestBody(`{"a":1}`), `{"a":1}`, true},
+		{"exact mismatch", WithRequestBody(`{"a":1}`), `{"a":2}`, false},
+		{"regex match", WithRequestBodyRegex(`^\{"a":\d+\}$`), `{"a":42}`, true},
+		{"regex mismatch", WithRequestBodyRegex(`^\{"a":\d+\}$`), `not json`, false},
+		{"json match ignores key order and whitespace", WithRequestBodyJSON(`{"a":1,"b":2}`), `{ "b": 2, "a": 1 }`, true},
+		{"json mismatch", WithRequestBodyJSON(`{"a":1}`), `{"a":2}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Stub("POST", "/things", "matched", tt.option)
+
+			req := httptest.NewRequest("POST", "/things", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			wantCode := http.StatusNotFound
+			if tt.matches {
+				wantCode = http.StatusOK
+			}
+			if rec.Code != wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package webmock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+// Response is a single response variant for a stub registered with
+// WithResponses.
+type Response struct {
+	Code    int
+	Body    string
+	Headers map[string]string
+}
+
+// WithResponses registers an ordered list of response variants for a
+// stub. By default each matching request advances to the next variant
+// one-shot, sticking on the last variant once the list is exhausted; this
+// is meant for simulating a transient failure followed by success, e.g.
+// WithResponses([]Response{{Code: 500, Body: "boom"}, {Code: 200, Body: "ok"}}).
+// Combine with WithCyclicResponses to wrap back to the first variant
+// instead of sticking on the last one.
+func WithResponses(responses []Response) FuncOption {
+	return func(r *route) {
+		r.responses = responses
+	}
+}
+
+// WithCyclicResponses makes a stub registered with WithResponses cycle
+// through its variants round-robin (wrapping back to the first once the
+// list is exhausted) instead of sticking on the last variant.
+func WithCyclicResponses() FuncOption {
+	return func(r *route) {
+		r.responsesCyclic = true
+	}
+}
+
+// response returns the status code, body and headers to serve for the
+// next matching request, taking WithResponses' sequencing into account.
+func (r *route) response() (int, string, map[string]string) {
+	if len(r.responses) == 0 {
+		return r.statusCode, r.body, r.responseHeaders
+	}
+
+	idx := atomic.AddUint32(&r.responseCursor, 1) - 1
+	n := uint32(len(r.responses))
+	if r.responsesCyclic {
+		idx = idx % n
+	} else if idx >= n {
+		idx = n - 1
+	}
+
+	variant := r.responses[idx]
+
+	return variant.Code, variant.Body, variant.Headers
+}
+
+// recordRequest captures r (with a re-readable copy of body) so it can
+// later be inspected via Requests, and bumps its call count for CallCount.
+func (s *MockServer) recordRequest(r *http.Request, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	captured := r.Clone(r.Context())
+	captured.Body = ioutil.NopCloser(bytes.NewReader(body))
+	s.capturedRequests = append(s.capturedRequests, captured)
+
+	s.callCounts[callCountKey(r.Method, r.URL.Path)]++
+}
+
+// CallCount returns how many requests for method and path the server has
+// received, matched or not. Requests rejected for exceeding the max body
+// size are not counted.
+func (s *MockServer) CallCount(method, path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.callCounts[callCountKey(method, path)]
+}
+
+// Requests returns copies of every request the server has received, in
+// arrival order, with their bodies buffered so they can be read again.
+// Requests rejected for exceeding the max body size are not included.
+func (s *MockServer) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]*http.Request, len(s.capturedRequests))
+	copy(requests, s.capturedRequests)
+
+	return requests
+}
+
+func callCountKey(method, path string) string {
+	return method + " " + path
+}
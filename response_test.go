@@ -0,0 +1,54 @@
+package webmock
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithResponsesOneShotSticksOnLastVariant(t *testing.T) {
+	s := New()
+	s.Stub("GET", "/seq", "", WithResponses([]Response{
+		{Code: 500, Body: "boom"},
+		{Code: 200, Body: "ok"},
+	}))
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("GET", "/seq", nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	want := []int{500, 200, 200, 200}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("codes = %v, want %v", codes, want)
+			break
+		}
+	}
+}
+
+func TestWithCyclicResponsesWrapsAround(t *testing.T) {
+	s := New()
+	s.Stub("GET", "/seq", "", WithResponses([]Response{
+		{Code: 500, Body: "boom"},
+		{Code: 200, Body: "ok"},
+	}), WithCyclicResponses())
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("GET", "/seq", nil)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+
+	want := []int{500, 200, 500, 200}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("codes = %v, want %v", codes, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package webmock
+
+import "strings"
+
+// pathTrie is a radix-style tree used to look up routes by their path
+// pattern in O(depth) time instead of scanning every registered route.
+// It supports three kinds of path segments: literal segments ("users"),
+// named parameters (":id") and a trailing catch-all ("*path").
+type pathTrie struct {
+	root *trieNode
+}
+
+type trieNode struct {
+	part          string
+	paramName     string
+	isCatchAll    bool
+	children      map[string]*trieNode
+	paramChild    *trieNode
+	catchAllChild *trieNode
+	routes        []*route
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[string]*trieNode)}
+}
+
+func newPathTrie() *pathTrie {
+	return &pathTrie{root: newTrieNode()}
+}
+
+// insert registers r under the given path pattern, e.g. "/users/:id" or
+// "/files/*path".
+func (t *pathTrie) insert(pattern string, r *route) {
+	t.root.insert(splitPath(pattern), r)
+}
+
+func (n *trieNode) insert(segments []string, r *route) {
+	if len(segments) == 0 {
+		n.routes = append(n.routes, r)
+		return
+	}
+
+	seg := segments[0]
+
+	switch {
+	case strings.HasPrefix(seg, ":"):
+		if n.paramChild == nil {
+			n.paramChild = newTrieNode()
+			n.paramChild.paramName = seg[1:]
+		}
+		n.paramChild.insert(segments[1:], r)
+	case strings.HasPrefix(seg, "*"):
+		if n.catchAllChild == nil {
+			n.catchAllChild = newTrieNode()
+			n.catchAllChild.isCatchAll = true
+			n.catchAllChild.paramName = seg[1:]
+		}
+		// a catch-all always terminates the pattern, regardless of how
+		// many segments follow it in the request path.
+		n.catchAllChild.routes = append(n.catchAllChild.routes, r)
+	default:
+		child, ok := n.children[seg]
+		if !ok {
+			child = newTrieNode()
+			child.part = seg
+			n.children[seg] = child
+		}
+		child.insert(segments[1:], r)
+	}
+}
+
+// lookup finds the node whose pattern matches path, filling params with any
+// named/catch-all segment values along the way. It returns nil if no
+// pattern matches. Static segments take priority over named parameters,
+// which take priority over catch-alls, backtracking as needed.
+func (t *pathTrie) lookup(path string, params map[string]string) *trieNode {
+	return t.root.lookup(splitPath(path), params)
+}
+
+func (n *trieNode) lookup(segments []string, params map[string]string) *trieNode {
+	if len(segments) == 0 {
+		if len(n.routes) > 0 {
+			return n
+		}
+		// A catch-all may still match the empty remainder.
+		if n.catchAllChild != nil {
+			params[n.catchAllChild.paramName] = ""
+			return n.catchAllChild
+		}
+		return nil
+	}
+
+	seg := segments[0]
+	rest := segments[1:]
+
+	if child, ok := n.children[seg]; ok {
+		if found := child.lookup(rest, params); found != nil {
+			return found
+		}
+	}
+
+	if n.paramChild != nil {
+		params[n.paramChild.paramName] = seg
+		if found := n.paramChild.lookup(rest, params); found != nil {
+			return found
+		}
+		delete(params, n.paramChild.paramName)
+	}
+
+	if n.catchAllChild != nil {
+		params[n.catchAllChild.paramName] = strings.Join(segments, "/")
+		return n.catchAllChild
+	}
+
+	return nil
+}
+
+// paramsForRoute recomputes r's named/catch-all parameters straight from
+// its own registered pattern against the segments the request actually
+// matched. It's used instead of the params map the trie lookup
+// accumulates while descending, because a trie node's param/catch-all
+// child is shared by every route inserted at that position: if two
+// routes share a position but declare different parameter names (e.g.
+// "/a/:id" and "/a/:name"), the trie only remembers whichever name
+// claimed the child first. Recomputing from the matched route's own
+// pattern means each route always renders under its own parameter
+// names, regardless of what else shares its position in the trie.
+func paramsForRoute(pattern string, requestSegments []string) map[string]string {
+	params := make(map[string]string)
+
+	for i, seg := range splitPath(pattern) {
+		if i >= len(requestSegments) {
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			params[seg[1:]] = requestSegments[i]
+		case strings.HasPrefix(seg, "*"):
+			params[seg[1:]] = strings.Join(requestSegments[i:], "/")
+		}
+	}
+
+	return params
+}
+
+func splitPath(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return nil
+	}
+
+	return strings.Split(trimmed, "/")
+}
@@ -0,0 +1,89 @@
+package webmock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestStubNamedParamRenderedInTemplate(t *testing.T) {
+	s := New()
+	s.Stub("GET", "/users/:id", "hello {{.Params.id}}")
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Body.String(); got != "hello 42" {
+		t.Errorf("body = %q, want %q", got, "hello 42")
+	}
+}
+
+func TestStubCatchAll(t *testing.T) {
+	s := New()
+	s.Stub("GET", "/files/*path", "{{.Params.path}}")
+
+	req := httptest.NewRequest("GET", "/files/a/b/c", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Body.String(); got != "a/b/c" {
+		t.Errorf("body = %q, want %q", got, "a/b/c")
+	}
+}
+
+// TestDifferentParamNamesAtSamePositionRenderIndependently guards against
+// two routes sharing a trie position (same method-less shape) but
+// declaring different parameter names: each must render under its own
+// name rather than whichever route first claimed that trie node.
+func TestDifferentParamNamesAtSamePositionRenderIndependently(t *testing.T) {
+	s := New()
+	s.Stub("GET", "/a/:id", "id={{.Params.id}}")
+	s.Stub("POST", "/a/:name", "name={{.Params.name}}")
+
+	getReq := httptest.NewRequest("GET", "/a/42", nil)
+	getRec := httptest.NewRecorder()
+	s.ServeHTTP(getRec, getReq)
+
+	if got, want := getRec.Body.String(), "id=42"; got != want {
+		t.Errorf("GET body = %q, want %q", got, want)
+	}
+
+	postReq := httptest.NewRequest("POST", "/a/alex", nil)
+	postRec := httptest.NewRecorder()
+	s.ServeHTTP(postRec, postReq)
+
+	if got, want := postRec.Body.String(), "name=alex"; got != want {
+		t.Errorf("POST body = %q, want %q", got, want)
+	}
+}
+
+// TestRawPathRoutingMatchesEncodedSlash guards against a route registered
+// with an encoded slash in a segment (`%2F`) failing to match under
+// WithRawPathRouting because the stub's pattern and the request's lookup
+// path were derived from different (escaped vs. unescaped) representations.
+func TestRawPathRoutingMatchesEncodedSlash(t *testing.T) {
+	s := New(WithRawPathRouting())
+	s.Stub("GET", "/files/a%2Fb", "matched")
+
+	req := httptest.NewRequest("GET", "/files/a%2Fb", nil)
+	u, _ := url.ParseRequestURI("/files/a%2Fb")
+	req.URL = u
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d body=%q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Body.String(); got != "matched" {
+		t.Errorf("body = %q, want %q", got, "matched")
+	}
+}
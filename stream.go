@@ -0,0 +1,137 @@
+package webmock
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// StreamChunk is one piece of a streaming response body, written and
+// flushed on its own, after waiting Delay.
+type StreamChunk struct {
+	Data  string
+	Delay time.Duration
+}
+
+// WithStreamingResponse makes a stub emit its body as a sequence of
+// chunks, each flushed via http.Flusher as soon as it's written, so
+// clients observe true streaming instead of one buffered write. Useful
+// for exercising streaming JSON/SSE parsers and client-side timeouts.
+func WithStreamingResponse(chunks []StreamChunk) FuncOption {
+	return func(r *route) {
+		r.streamChunks = chunks
+	}
+}
+
+// WithDelay adds a fixed latency before a stub starts responding.
+func WithDelay(d time.Duration) FuncOption {
+	return func(r *route) {
+		r.delay = d
+	}
+}
+
+// WithBandwidth throttles a stub's response body to roughly
+// bytesPerSecond, to exercise slow-consumer and timeout handling.
+func WithBandwidth(bytesPerSecond int) FuncOption {
+	return func(r *route) {
+		r.bandwidth = bytesPerSecond
+	}
+}
+
+// WithEarlyClose makes a stub reset the underlying connection without
+// writing a response, simulating a broken connection for retry/timeout
+// testing. It requires the ResponseWriter to support http.Hijacker, which
+// HTTP/2 connections (see StartTLS) don't; over HTTP/2 it falls back to
+// whatever close semantics net/http applies when the handler returns
+// without writing a response.
+func WithEarlyClose() FuncOption {
+	return func(r *route) {
+		r.earlyClose = true
+	}
+}
+
+// closeAbruptly hijacks the connection and resets it (TCP RST) rather
+// than closing it cleanly (FIN), so clients observe "connection reset by
+// peer" instead of a clean EOF. If the writer can't be hijacked (e.g.
+// serving over HTTP/2), it just returns without writing.
+func closeAbruptly(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+
+	conn.Close()
+}
+
+// writeStreamingResponse writes statusCode and headers, then each chunk in
+// order, flushing after every write so the client sees them as they're
+// produced rather than buffered until the handler returns.
+func writeStreamingResponse(w http.ResponseWriter, statusCode int, headers map[string]string, chunks []StreamChunk, bandwidth int) {
+	for headerKey, headerVal := range headers {
+		w.Header().Set(headerKey, headerVal)
+	}
+
+	w.WriteHeader(statusCode)
+
+	flusher, canFlush := w.(http.Flusher)
+
+	for _, chunk := range chunks {
+		if chunk.Delay > 0 {
+			time.Sleep(chunk.Delay)
+		}
+
+		writeThrottled(w, []byte(chunk.Data), bandwidth)
+
+		if canFlush {
+			flusher.Flush()
+		}
+	}
+}
+
+// bandwidthTick is how often writeThrottled wakes up to write its next
+// slice; bandwidth is spent in bandwidthTick-sized slices rather than
+// byte-by-byte to keep the number of writes reasonable.
+const bandwidthTick = 100 * time.Millisecond
+
+// writeThrottled writes data to w at roughly bytesPerSecond. A
+// bytesPerSecond of 0 or less disables throttling and writes data in one
+// shot.
+func writeThrottled(w http.ResponseWriter, data []byte, bytesPerSecond int) {
+	if bytesPerSecond <= 0 {
+		w.Write(data)
+		return
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+
+	sliceSize := bytesPerSecond / int(time.Second/bandwidthTick)
+	if sliceSize < 1 {
+		sliceSize = 1
+	}
+
+	for written := 0; written < len(data); {
+		end := written + sliceSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		w.Write(data[written:end])
+		if canFlush {
+			flusher.Flush()
+		}
+
+		written = end
+		if written < len(data) {
+			time.Sleep(bandwidthTick)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package webmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForListener retries a GET until Start's asynchronous listener setup
+// has finished, instead of racing it.
+func waitForListener(url string) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = http.Get(url)
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			return resp, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return resp, err
+}
+
+// TestWithEarlyCloseResetsConnection asserts the client sees a hard reset
+// (ECONNRESET) rather than a clean EOF, since retry policies commonly
+// treat the two differently.
+func TestWithEarlyCloseResetsConnection(t *testing.T) {
+	s := New()
+	s.Start()
+	defer s.Stop()
+
+	s.Stub("GET", "/broken", "", WithEarlyClose())
+
+	_, err := waitForListener(s.URL() + "/broken")
+	if err == nil {
+		t.Fatal("expected an error from a reset connection, got nil")
+	}
+	if !strings.Contains(err.Error(), "reset") {
+		t.Errorf("unexpected error, want a connection reset: %v", err)
+	}
+}
+
+func TestWithStreamingResponseWritesChunksInOrder(t *testing.T) {
+	s := New()
+	s.Start()
+	defer s.Stop()
+
+	s.Stub("GET", "/stream", "", WithStreamingResponse([]StreamChunk{
+		{Data: "chunk1"},
+		{Data: "chunk2"},
+	}))
+
+	resp, err := waitForListener(s.URL() + "/stream")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if got, want := string(body), "chunk1chunk2"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package webmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// StartTLS starts the mock server over HTTPS with HTTP/2 enabled. If a
+// tlsConfig is given, it's used as-is (e.g. to bring your own certificate);
+// otherwise an in-memory self-signed certificate is generated, retrievable
+// afterwards via Certificate. Mirrors the ergonomics of httptest.Server.
+func (s *MockServer) StartTLS(tlsConfig ...*tls.Config) {
+	var cfg *tls.Config
+	if len(tlsConfig) > 0 && tlsConfig[0] != nil {
+		cfg = tlsConfig[0]
+	} else {
+		cert, err := generateSelfSignedCertificate()
+		if err != nil {
+			log.Fatal("generate self-signed certificate fail: ", err)
+		}
+		cfg = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	if len(cfg.Certificates) > 0 {
+		leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+		if err == nil {
+			s.certificate = leaf
+		}
+	}
+
+	s.isTLS = true
+	s.server.Handler = s
+	s.server.TLSConfig = cfg
+
+	if err := http2.ConfigureServer(s.server, &http2.Server{}); err != nil {
+		log.Fatal("configure http2 fail: ", err)
+	}
+
+	go func() {
+		err := s.server.ListenAndServeTLS("", "")
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal("fail to serve : ", err)
+		}
+	}()
+}
+
+// Certificate returns the server's TLS certificate, or nil if StartTLS
+// hasn't been called.
+func (s *MockServer) Certificate() *x509.Certificate {
+	return s.certificate
+}
+
+// Client returns an *http.Client preconfigured to trust the server's
+// certificate and to use HTTP/2 when talking to it.
+func (s *MockServer) Client() *http.Client {
+	if !s.isTLS || s.certificate == nil {
+		return &http.Client{}
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(s.certificate)
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	http2.ConfigureTransport(transport)
+
+	return &http.Client{Transport: transport}
+}
+
+func generateSelfSignedCertificate() (tls.Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "webmock"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * 365 * time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
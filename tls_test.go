@@ -0,0 +1,49 @@
+package webmock
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartTLSServesOverHTTPSWithGeneratedCert(t *testing.T) {
+	s := New()
+	s.StartTLS()
+	defer s.Stop()
+
+	if !strings.HasPrefix(s.URL(), "https://") {
+		t.Fatalf("URL() = %q, want an https:// scheme", s.URL())
+	}
+	if s.Certificate() == nil {
+		t.Fatal("Certificate() = nil after StartTLS")
+	}
+
+	s.Stub("GET", "/ping", "pong")
+
+	// StartTLS's listener comes up asynchronously; retry briefly instead
+	// of racing it.
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = s.Client().Get(s.URL() + "/ping")
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("client.Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "pong" {
+		t.Errorf("body = %q, want %q", body, "pong")
+	}
+}
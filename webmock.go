@@ -1,7 +1,9 @@
 package webmock
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,32 +14,80 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 // MockServer holds mock server and routes
 type MockServer struct {
-	addr   string
-	server *http.Server
-	routes []*route
+	addr           string
+	server         *http.Server
+	routes         []*route
+	router         *pathTrie
+	rawPathRouting bool
+	recording      *recordSession
+	maxBodySize    int64
+
+	mu               sync.Mutex
+	capturedRequests []*http.Request
+	callCounts       map[string]int
+
+	isTLS       bool
+	certificate *x509.Certificate
 }
 
 type route struct {
-	domain          string
-	method          string
-	path            string
-	query           string
-	requestHeaders  map[string]string
-	statusCode      int
-	body            string
-	responseHeaders map[string]string
+	domain             string
+	method             string
+	path               string
+	query              string
+	requestHeaders     map[string]string
+	requestBodyMatcher bodyMatcher
+	statusCode         int
+	body               string
+	responseHeaders    map[string]string
+	responses          []Response
+	responsesCyclic    bool
+	responseCursor     uint32
+	delay              time.Duration
+	bandwidth          int
+	earlyClose         bool
+	streamChunks       []StreamChunk
 	//TODO: payload
 }
 
 // FuncOption is the option for a route
 type FuncOption func(*route)
 
+// MockServerOption is the option for a MockServer
+type MockServerOption func(*MockServer)
+
+// WithRawPathRouting makes the server match stubbed paths against the raw,
+// still-escaped request URI instead of the unescaped `url.Path`. This is
+// needed to stub routes whose path segments contain an encoded slash
+// (`%2F`), which `net/http` would otherwise unescape before matching.
+func WithRawPathRouting() MockServerOption {
+	return func(s *MockServer) {
+		s.rawPathRouting = true
+	}
+}
+
+// defaultMaxBodySize caps how much of an incoming request body ServeHTTP
+// will buffer in order to apply a request body matcher.
+const defaultMaxBodySize = 10 << 20 // 10MB
+
+// WithMaxBodySize overrides the default limit on how much of a request
+// body is buffered for matching against WithRequestBody(Regex|JSON).
+// Requests whose body exceeds the limit get a 413 response.
+func WithMaxBodySize(n int64) MockServerOption {
+	return func(s *MockServer) {
+		s.maxBodySize = n
+	}
+}
+
 type cassetteRoute struct {
 	Request  httpRequest  `yaml:"request"`
 	Response httpResponse `yaml:"response"`
@@ -49,6 +99,7 @@ type httpRequest struct {
 	Method  string            `yaml:"method"`
 	Path    string            `yaml:"path"`
 	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body,omitempty"`
 }
 
 type httpResponse struct {
@@ -58,7 +109,7 @@ type httpResponse struct {
 }
 
 // New creates a mock server, it will listen on a unoccupied port
-func New() *MockServer {
+func New(options ...MockServerOption) *MockServer {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		log.Fatal("allocate listen address fail: ", err)
@@ -72,11 +123,20 @@ func New() *MockServer {
 		Addr: addr,
 	}
 
-	return &MockServer{
-		addr:   addr,
-		server: srv,
-		routes: make([]*route, 0),
+	s := &MockServer{
+		addr:        addr,
+		server:      srv,
+		routes:      make([]*route, 0),
+		router:      newPathTrie(),
+		maxBodySize: defaultMaxBodySize,
+		callCounts:  make(map[string]int),
 	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	return s
 }
 
 // Start starts the mock server in a goroutine
@@ -103,15 +163,29 @@ func (s *MockServer) Stop() {
 // Reset resets mocked routes
 func (s *MockServer) Reset() {
 	s.routes = make([]*route, 0)
+	s.router = newPathTrie()
 	s.server.Handler = s
+
+	s.mu.Lock()
+	s.capturedRequests = nil
+	s.callCounts = make(map[string]int)
+	s.mu.Unlock()
 }
 
 // URL returns the base URL of the mock server
 func (s *MockServer) URL() string {
-	return fmt.Sprintf("http://%s", s.addr)
+	scheme := "http"
+	if s.isTLS {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, s.addr)
 }
 
-// Stub loads stub requests into routes
+// Stub loads stub requests into routes. The path may contain named
+// parameters (`/users/:id`) and a trailing catch-all (`/files/*path`);
+// their matched values are exposed to the response body as
+// `{{.Params.id}}` via text/template.
 func (s *MockServer) Stub(method, uri string, response string, options ...FuncOption) {
 	url, err := url.Parse(uri)
 	if err != nil {
@@ -120,7 +194,7 @@ func (s *MockServer) Stub(method, uri string, response string, options ...FuncOp
 
 	r := &route{
 		domain: url.Host,
-		path:   url.Path,
+		path:   routePath(url, s.rawPathRouting),
 		method: method,
 		query:  url.RawQuery,
 		body:   response,
@@ -130,7 +204,25 @@ func (s *MockServer) Stub(method, uri string, response string, options ...FuncOp
 		opt(r)
 	}
 
+	s.addRoute(r)
+}
+
+// routePath returns the path a route should be indexed under: the
+// auto-unescaped `url.Path` normally, or the still-escaped
+// `url.EscapedPath()` when rawPathRouting is on, so it matches the same
+// representation ServeHTTP looks up requests by (see WithRawPathRouting).
+func routePath(u *url.URL, rawPathRouting bool) string {
+	if rawPathRouting {
+		return u.EscapedPath()
+	}
+
+	return u.Path
+}
+
+// addRoute appends r to the route list and indexes it for lookup.
+func (s *MockServer) addRoute(r *route) {
 	s.routes = append(s.routes, r)
+	s.router.insert(r.path, r)
 
 	s.server.Handler = s
 }
@@ -150,18 +242,19 @@ func (s *MockServer) LoadCassette(path string) {
 
 	switch mode := stat.Mode(); {
 	case mode.IsDir():
-		r := loadCassettes(path)
+		r := loadCassettes(path, s.rawPathRouting)
 		routes = append(routes, r...)
 	case mode.IsRegular():
-		r := loadCassette(path)
+		r := loadCassette(path, s.rawPathRouting)
 		routes = append(routes, r...)
 	}
 
-	s.routes = append(s.routes, routes...)
-	s.server.Handler = s
+	for _, r := range routes {
+		s.addRoute(r)
+	}
 }
 
-func loadCassettes(dirPath string) []*route {
+func loadCassettes(dirPath string, rawPathRouting bool) []*route {
 	files, err := ioutil.ReadDir(dirPath)
 	if err != nil {
 		log.Fatal("read dir fails: ", err)
@@ -169,14 +262,14 @@ func loadCassettes(dirPath string) []*route {
 
 	var routes []*route
 	for _, f := range files {
-		r := loadCassette(path.Join(dirPath, f.Name()))
+		r := loadCassette(path.Join(dirPath, f.Name()), rawPathRouting)
 		routes = append(routes, r...)
 	}
 
 	return routes
 }
 
-func loadCassette(filePath string) []*route {
+func loadCassette(filePath string, rawPathRouting bool) []*route {
 	file, err := os.Open(filePath)
 	if err != nil {
 		log.Fatalf("fail to read file: %s, err: %s", filePath, err)
@@ -197,7 +290,7 @@ func loadCassette(filePath string) []*route {
 		}
 
 		r := &route{
-			path:            url.Path,
+			path:            routePath(url, rawPathRouting),
 			method:          strings.ToUpper(c.Request.Method),
 			query:           url.RawQuery,
 			body:            c.Response.Body,
@@ -205,6 +298,10 @@ func loadCassette(filePath string) []*route {
 			statusCode:      c.Response.Status,
 		}
 
+		if c.Request.Body != "" {
+			r.requestBodyMatcher = exactBodyMatcher(c.Request.Body)
+		}
+
 		routes = append(routes, r)
 	}
 
@@ -212,45 +309,134 @@ func loadCassette(filePath string) []*route {
 }
 
 // ServeHTTP implements the server.Handler
-// It go over all existing routes and find the one matches and render response
-// based on the found route
+// It looks up the route trie for the requested path and, among the
+// candidates registered under the matched pattern, finds the one whose
+// method, query and headers match, rendering its response.
 func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var routeFound *route
+	body, err := bufferRequestBody(r, s.maxBodySize)
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
 
-	for _, route := range s.routes {
-		if routeMatch(route, r) {
-			routeFound = route
-		}
+	s.recordRequest(r, body)
+
+	requestPath := r.URL.Path
+	if s.rawPathRouting {
+		requestPath = r.URL.EscapedPath()
+	}
+
+	node := s.router.lookup(requestPath, make(map[string]string))
+
+	var routeFound *route
+	if node != nil {
+		routeFound = routeMatch(node.routes, r, body)
 	}
 
 	if routeFound == nil {
+		if s.recording != nil {
+			s.recordAndProxy(w, r)
+			return
+		}
+
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	for headerKey, headerVal := range routeFound.responseHeaders {
-		w.Header().Set(headerKey, headerVal)
+	statusCode, respBody, headers := routeFound.response()
+
+	if routeFound.delay > 0 {
+		time.Sleep(routeFound.delay)
+	}
+
+	if routeFound.earlyClose {
+		closeAbruptly(w)
+		return
 	}
 
-	statusCode := routeFound.statusCode
 	if statusCode == 0 {
 		statusCode = http.StatusOK
 	}
 
+	if len(routeFound.streamChunks) > 0 {
+		writeStreamingResponse(w, statusCode, headers, routeFound.streamChunks, routeFound.bandwidth)
+		return
+	}
+
+	for headerKey, headerVal := range headers {
+		w.Header().Set(headerKey, headerVal)
+	}
+
 	w.WriteHeader(statusCode)
 
-	io.WriteString(w, routeFound.body)
+	params := paramsForRoute(routeFound.path, splitPath(requestPath))
+	writeThrottled(w, []byte(renderBody(respBody, params)), routeFound.bandwidth)
+}
+
+// routeMatch picks the last of candidates whose method, query, headers and
+// body match r, preserving the existing "later stub wins" override
+// behaviour.
+func routeMatch(candidates []*route, r *http.Request, body []byte) *route {
+	var found *route
+
+	for _, route := range candidates {
+		if route.method == r.Method &&
+			route.query == r.URL.RawQuery &&
+			headersMatch(route.requestHeaders, r.Header) &&
+			(route.requestBodyMatcher == nil || route.requestBodyMatcher(body, r)) {
+			found = route
+		}
+	}
+
+	return found
 }
 
-func routeMatch(route *route, r *http.Request) bool {
-	if route.path == r.URL.Path &&
-		route.method == r.Method &&
-		route.query == r.URL.RawQuery &&
-		headersMatch(route.requestHeaders, r.Header) {
-		return true
+// bufferRequestBody reads r.Body (up to maxBodySize) so it can be matched
+// against a request body matcher, then restores it so downstream code
+// (recording's upstream proxy) can still read it; recordRequest gets the
+// returned copy directly. Buffering the whole body trades memory for the ability
+// to match it more than once; maxBodySize bounds that cost.
+func bufferRequestBody(r *http.Request, maxBodySize int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	limited := io.LimitReader(r.Body, maxBodySize+1)
+	body, err := ioutil.ReadAll(limited)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > maxBodySize {
+		return nil, fmt.Errorf("request body exceeds max size of %d bytes", maxBodySize)
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// templateData is the data made available to a stubbed response body when
+// it is rendered as a text/template.
+type templateData struct {
+	Params map[string]string
+}
+
+// renderBody executes body as a text/template with the matched path
+// parameters. If body isn't a valid template, or rendering fails, the
+// original body is returned unchanged.
+func renderBody(body string, params map[string]string) string {
+	tmpl, err := template.New("response").Parse(body)
+	if err != nil {
+		return body
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData{Params: params}); err != nil {
+		return body
 	}
 
-	return false
+	return buf.String()
 }
 
 func headersMatch(routeHeaders map[string]string, requestHeader http.Header) bool {